@@ -0,0 +1,47 @@
+package driver
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// sealAWSEnvelope encrypts plaintext under a fresh AES-256 data key
+// generated by AWS KMS (kmsKeyID), returning the JSON envelope produced
+// by sealWithDataKey.
+func sealAWSEnvelope(sess *session.Session, kmsKeyID string, plaintext []byte) ([]byte, error) {
+	svc := kms.New(sess)
+
+	dataKey, err := svc.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:   aws.String(kmsKeyID),
+		KeySpec: aws.String("AES_256"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generating kms data key: %s", err)
+	}
+
+	return sealWithDataKey(plaintext, dataKey.Plaintext, dataKey.CiphertextBlob)
+}
+
+// openAWSEnvelope unwraps the data key embedded in an envelope produced
+// by sealAWSEnvelope via AWS KMS Decrypt, then decrypts the payload.
+func openAWSEnvelope(sess *session.Session, kmsKeyID string, blob []byte) ([]byte, error) {
+	wrappedKey, err := wrappedKeyFromEnvelope(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := kms.New(sess)
+
+	unwrapped, err := svc.Decrypt(&kms.DecryptInput{
+		KeyId:          aws.String(kmsKeyID),
+		CiphertextBlob: wrappedKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping kms data key: %s", err)
+	}
+
+	return openWithDataKey(blob, unwrapped.Plaintext)
+}
@@ -2,13 +2,18 @@ package driver
 
 import (
 	"fmt"
+	"os"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/credentials/endpointcreds"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/blang/semver"
 	"github.com/concourse/semver-resource/models"
 	"github.com/concourse/semver-resource/version"
@@ -37,45 +42,15 @@ func FromSource(source models.Source) (Driver, error) {
 
 	switch source.Driver {
 	case models.DriverUnspecified, models.DriverS3:
-		var creds *credentials.Credentials
-
-		sess := session.Must(session.NewSession())
-
-		if source.AccessKeyID == "" && source.SecretAccessKey == "" {
-			if source.RoleArn == "" {
-				creds = credentials.AnonymousCredentials
-			} else {
-				// Initial credentials loaded from EC2 instance
-				// role. These credentials will be used to make the STS Assume Role API.
-
-				creds = credentials.NewCredentials(
-					&ec2rolecreds.EC2RoleProvider{
-						Client: ec2metadata.New(session.New()),
-					},
-				)
-				_, err := creds.Get()
-				// If unsuccessful fall back to anonymous
-				if err != nil {
-					creds = credentials.AnonymousCredentials
-				} else {
-					creds = credentials.NewStaticCredentials(source.AccessKeyID, source.SecretAccessKey, "")
-				}
-
-				// Create the credentials from AssumeRoleProvider to assume the role
-				// referenced by RoleArn.
-				creds = stscreds.NewCredentials(sess, source.RoleArn)
-
-			}
-		} else {
-			// Use provided AWS keys
-			creds = credentials.NewStaticCredentials(source.AccessKeyID, source.SecretAccessKey, "")
-		}
-
 		regionName := source.RegionName
 		if len(regionName) == 0 {
 			regionName = "us-east-1"
 		}
 
+		sess := session.Must(session.NewSession(&aws.Config{Region: aws.String(regionName)}))
+
+		creds := s3Credentials(source, sess)
+
 		awsConfig := &aws.Config{
 			Region:           aws.String(regionName),
 			Credentials:      creds,
@@ -89,16 +64,21 @@ func FromSource(source models.Source) (Driver, error) {
 		}
 
 		svc := s3.New(sess, awsConfig)
-		// Create service client value configured for credentials
-		// from assumed role.
-		// svc := s3.New(sess, &aws.Config{Credentials: creds})
 
 		return &S3Driver{
 			InitialVersion:       initialVersion,
+			Sess:                 sess,
 			Svc:                  svc,
 			BucketName:           source.Bucket,
 			Key:                  source.Key,
 			ServerSideEncryption: source.ServerSideEncryption,
+
+			UseS3Versioning:       source.UseS3Versioning,
+			ObjectLockMode:        source.ObjectLockMode,
+			ObjectLockRetainUntil: source.ObjectLockRetainUntil,
+
+			KMSKeyID:       source.KMSKeyID,
+			EncryptionMode: source.EncryptionMode,
 		}, nil
 	case models.DriverGit:
 		return &GitDriver{
@@ -116,6 +96,22 @@ func FromSource(source models.Source) (Driver, error) {
 	case models.DriverSwift:
 		return NewSwiftDriver(&source)
 
+	case models.DriverHTTP:
+		return &HTTPDriver{
+			InitialVersion: initialVersion,
+
+			URI:    source.URI,
+			WebDAV: source.WebDAV,
+
+			Username: source.Username,
+			Password: source.Password,
+			Token:    source.Token,
+
+			CACert:     source.CACert,
+			ClientCert: source.ClientCert,
+			ClientKey:  source.ClientKey,
+		}, nil
+
 	case models.DriverGCS:
 		servicer := &GCSIOServicer{
 			JSONCredentials: source.JSONKey,
@@ -127,9 +123,101 @@ func FromSource(source models.Source) (Driver, error) {
 			Servicer:   servicer,
 			BucketName: source.Bucket,
 			Key:        source.Key,
+
+			KMSKeyID:       source.KMSKeyID,
+			EncryptionMode: source.EncryptionMode,
 		}, nil
 
 	default:
 		return nil, fmt.Errorf("unknown driver: %s", source.Driver)
 	}
 }
+
+// s3Credentials builds an ordered credential provider chain for the S3
+// driver, modeled on defaults.CredProviders: static keys, environment
+// variables, AWS SSO (when SSOStartURL is set), a shared credentials
+// file, IRSA (EKS web identity), ECS
+// container credentials, and finally the EC2 instance role. If the
+// source specifies a RoleArn, the resulting base credentials are used
+// to authenticate an AssumeRole call and the assumed-role credentials
+// are returned instead.
+func s3Credentials(source models.Source, sess *session.Session) *credentials.Credentials {
+	regionName := source.RegionName
+	if regionName == "" {
+		regionName = "us-east-1"
+	}
+
+	stsSess := sess.Copy(&aws.Config{Region: aws.String(regionName)})
+
+	providers := []credentials.Provider{}
+
+	if source.AccessKeyID != "" || source.SecretAccessKey != "" {
+		providers = append(providers, &credentials.StaticProvider{
+			Value: credentials.Value{
+				AccessKeyID:     source.AccessKeyID,
+				SecretAccessKey: source.SecretAccessKey,
+				SessionToken:    source.SessionToken,
+			},
+		})
+	}
+
+	providers = append(providers, &credentials.EnvProvider{})
+
+	if source.SSOStartURL != "" {
+		providers = append(providers, newSSOCredentialsProvider(sess, source))
+	}
+
+	providers = append(providers, &credentials.SharedCredentialsProvider{
+		Filename: source.SharedCredentialsFile,
+		Profile:  source.Profile,
+	})
+
+	if webIdentityTokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); webIdentityTokenFile != "" {
+		if roleArn := os.Getenv("AWS_ROLE_ARN"); roleArn != "" {
+			providers = append(providers, stscreds.NewWebIdentityRoleProvider(
+				sts.New(stsSess), roleArn, source.RoleSessionName, webIdentityTokenFile,
+			))
+		}
+	}
+
+	if relativeURI := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relativeURI != "" {
+		providers = append(providers, endpointcreds.NewProviderClient(
+			*sess.Config, sess.Handlers, "http://169.254.170.2"+relativeURI,
+		))
+	} else if fullURI := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"); fullURI != "" {
+		providers = append(providers, endpointcreds.NewProviderClient(
+			*sess.Config, sess.Handlers, fullURI,
+		))
+	}
+
+	providers = append(providers, &ec2rolecreds.EC2RoleProvider{
+		Client: ec2metadata.New(sess),
+	})
+
+	baseCreds := credentials.NewChainCredentials(providers)
+
+	if source.RoleArn == "" {
+		if _, err := baseCreds.Get(); err != nil {
+			return credentials.AnonymousCredentials
+		}
+
+		return baseCreds
+	}
+
+	assumeRoleCreds := stscreds.NewCredentialsWithClient(sts.New(stsSess, &aws.Config{Credentials: baseCreds}), source.RoleArn, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = source.RoleSessionName
+		if source.ExternalID != "" {
+			p.ExternalID = aws.String(source.ExternalID)
+		}
+
+		if source.AssumeRoleDurationSeconds != 0 {
+			p.Duration = time.Duration(source.AssumeRoleDurationSeconds) * time.Second
+		}
+	})
+
+	if _, err := assumeRoleCreds.Get(); err != nil {
+		return credentials.AnonymousCredentials
+	}
+
+	return assumeRoleCreds
+}
@@ -0,0 +1,76 @@
+package driver
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+)
+
+// envelope is the on-disk representation of a client-side envelope
+// encrypted payload: an AES-256-GCM ciphertext alongside the data key
+// that encrypted it, wrapped by whichever KMS produced it.
+type envelope struct {
+	Ciphertext []byte `json:"ciphertext"`
+	Nonce      []byte `json:"nonce"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+func sealWithDataKey(plaintext, plaintextKey, wrappedKey []byte) ([]byte, error) {
+	block, err := aes.NewCipher(plaintextKey)
+	if err != nil {
+		return nil, fmt.Errorf("initializing aes cipher: %s", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("initializing aes-gcm: %s", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %s", err)
+	}
+
+	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+
+	return json.Marshal(envelope{
+		Ciphertext: ciphertext,
+		Nonce:      nonce,
+		WrappedKey: wrappedKey,
+	})
+}
+
+func openWithDataKey(blob, plaintextKey []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(blob, &env); err != nil {
+		return nil, fmt.Errorf("parsing envelope: %s", err)
+	}
+
+	block, err := aes.NewCipher(plaintextKey)
+	if err != nil {
+		return nil, fmt.Errorf("initializing aes cipher: %s", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("initializing aes-gcm: %s", err)
+	}
+
+	plaintext, err := gcm.Open(nil, env.Nonce, env.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting envelope: %s", err)
+	}
+
+	return plaintext, nil
+}
+
+func wrappedKeyFromEnvelope(blob []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(blob, &env); err != nil {
+		return nil, fmt.Errorf("parsing envelope: %s", err)
+	}
+
+	return env.WrappedKey, nil
+}
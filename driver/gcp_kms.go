@@ -0,0 +1,66 @@
+package driver
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	cloudkms "cloud.google.com/go/kms/apiv1"
+	kmspb "google.golang.org/genproto/googleapis/cloud/kms/v1"
+)
+
+// sealGCPEnvelope generates a fresh local AES-256 data key, wraps it
+// with Cloud KMS Encrypt (kmsKeyID is a full
+// projects/.../locations/.../keyRings/.../cryptoKeys/... resource
+// name), and returns the JSON envelope produced by sealWithDataKey.
+func sealGCPEnvelope(kmsKeyID string, plaintext []byte) ([]byte, error) {
+	ctx := context.Background()
+
+	client, err := cloudkms.NewKeyManagementClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating cloud kms client: %s", err)
+	}
+	defer client.Close()
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("generating data key: %s", err)
+	}
+
+	resp, err := client.Encrypt(ctx, &kmspb.EncryptRequest{
+		Name:      kmsKeyID,
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("wrapping data key via cloud kms: %s", err)
+	}
+
+	return sealWithDataKey(plaintext, dataKey, resp.Ciphertext)
+}
+
+// openGCPEnvelope unwraps the data key embedded in an envelope produced
+// by sealGCPEnvelope via Cloud KMS Decrypt, then decrypts the payload.
+func openGCPEnvelope(kmsKeyID string, blob []byte) ([]byte, error) {
+	wrappedKey, err := wrappedKeyFromEnvelope(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	client, err := cloudkms.NewKeyManagementClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating cloud kms client: %s", err)
+	}
+	defer client.Close()
+
+	resp, err := client.Decrypt(ctx, &kmspb.DecryptRequest{
+		Name:       kmsKeyID,
+		Ciphertext: wrappedKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping data key via cloud kms: %s", err)
+	}
+
+	return openWithDataKey(blob, resp.Plaintext)
+}
@@ -0,0 +1,158 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"cloud.google.com/go/storage"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/version"
+)
+
+// GCSIOServicer is a thin wrapper around the GCS client library that
+// downloads and uploads a single object for GCSDriver. It is a concrete
+// type that always talks to GCS.
+type GCSIOServicer struct {
+	JSONCredentials string
+}
+
+func (servicer *GCSIOServicer) client(ctx context.Context) (*storage.Client, error) {
+	if servicer.JSONCredentials == "" {
+		return storage.NewClient(ctx)
+	}
+
+	creds, err := google.CredentialsFromJSON(ctx, []byte(servicer.JSONCredentials), storage.ScopeReadWrite)
+	if err != nil {
+		return nil, fmt.Errorf("parsing json_key: %s", err)
+	}
+
+	return storage.NewClient(ctx, option.WithCredentials(creds))
+}
+
+func (servicer *GCSIOServicer) Download(bucket, object string) ([]byte, error) {
+	ctx := context.Background()
+
+	client, err := servicer.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	reader, err := client.Bucket(bucket).Object(object).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+func (servicer *GCSIOServicer) Upload(bucket, object string, contents []byte) error {
+	ctx := context.Background()
+
+	client, err := servicer.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	writer := client.Bucket(bucket).Object(object).NewWriter(ctx)
+
+	if _, err := writer.Write(contents); err != nil {
+		writer.Close()
+		return err
+	}
+
+	return writer.Close()
+}
+
+// GCSDriver stores the current version as the contents of a single
+// object in a GCS bucket.
+type GCSDriver struct {
+	InitialVersion semver.Version
+
+	Servicer   *GCSIOServicer
+	BucketName string
+	Key        string
+
+	KMSKeyID       string
+	EncryptionMode string
+}
+
+func (driver *GCSDriver) Bump(bump version.Bump) (semver.Version, error) {
+	currentVersion, err := driver.readVersion()
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	newVersion := bump.Apply(currentVersion)
+
+	if err := driver.writeVersion(newVersion); err != nil {
+		return semver.Version{}, err
+	}
+
+	return newVersion, nil
+}
+
+func (driver *GCSDriver) Set(newVersion semver.Version) error {
+	return driver.writeVersion(newVersion)
+}
+
+func (driver *GCSDriver) Check(version *semver.Version) ([]semver.Version, error) {
+	currentVersion, err := driver.readVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if version == nil || currentVersion.GTE(*version) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	return []semver.Version{}, nil
+}
+
+func (driver *GCSDriver) readVersion() (semver.Version, error) {
+	contents, err := driver.Servicer.Download(driver.BucketName, driver.Key)
+	if err != nil {
+		if err == storage.ErrObjectNotExist {
+			return driver.InitialVersion, nil
+		}
+
+		return semver.Version{}, err
+	}
+
+	if driver.EncryptionMode == "cse-kms" {
+		contents, err = openGCPEnvelope(driver.KMSKeyID, contents)
+		if err != nil {
+			return semver.Version{}, err
+		}
+	}
+
+	currentVersion, err := semver.Parse(string(bytes.TrimSpace(contents)))
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("parsing current version: %s", err)
+	}
+
+	return currentVersion, nil
+}
+
+func (driver *GCSDriver) writeVersion(newVersion semver.Version) error {
+	contents := []byte(newVersion.String())
+
+	if driver.EncryptionMode == "cse-kms" {
+		sealed, err := sealGCPEnvelope(driver.KMSKeyID, contents)
+		if err != nil {
+			return err
+		}
+
+		contents = sealed
+	}
+
+	return driver.Servicer.Upload(driver.BucketName, driver.Key, contents)
+}
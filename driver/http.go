@@ -0,0 +1,292 @@
+package driver
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/version"
+)
+
+// maxCASAttempts bounds the If-Match compare-and-swap retry loop in
+// Bump/Set so that sustained contention between concurrent bumps fails
+// loudly instead of spinning forever.
+const maxCASAttempts = 10
+
+// HTTPDriver stores the current version as the body of a single
+// resource fetched/written over HTTP(S), optionally through WebDAV's
+// LOCK/PUT/UNLOCK sequence. Writes are guarded by an If-Match
+// compare-and-swap loop keyed off the resource's ETag so that two
+// concurrent bumps can't silently clobber one another.
+type HTTPDriver struct {
+	InitialVersion semver.Version
+
+	URI    string
+	WebDAV bool
+
+	Username string
+	Password string
+	Token    string
+
+	CACert     string
+	ClientCert string
+	ClientKey  string
+
+	client *http.Client
+}
+
+func (driver *HTTPDriver) Bump(bump version.Bump) (semver.Version, error) {
+	for attempt := 0; attempt < maxCASAttempts; attempt++ {
+		currentVersion, etag, err := driver.get()
+		if err != nil {
+			return semver.Version{}, err
+		}
+
+		newVersion := bump.Apply(currentVersion)
+
+		err = driver.put(newVersion, etag)
+		if err == errETagMismatch {
+			casBackoff(attempt)
+			continue
+		}
+		if err != nil {
+			return semver.Version{}, err
+		}
+
+		return newVersion, nil
+	}
+
+	return semver.Version{}, fmt.Errorf("giving up after %d attempts: %s", maxCASAttempts, errETagMismatch)
+}
+
+func (driver *HTTPDriver) Set(newVersion semver.Version) error {
+	for attempt := 0; attempt < maxCASAttempts; attempt++ {
+		_, etag, err := driver.get()
+		if err != nil {
+			return err
+		}
+
+		err = driver.put(newVersion, etag)
+		if err == errETagMismatch {
+			casBackoff(attempt)
+			continue
+		}
+
+		return err
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %s", maxCASAttempts, errETagMismatch)
+}
+
+// casBackoff sleeps with a jittered linear backoff between If-Match
+// compare-and-swap retries so that concurrent bumpers don't lock-step
+// retry against one another.
+func casBackoff(attempt int) {
+	base := time.Duration(attempt+1) * 100 * time.Millisecond
+	jitter := time.Duration(rand.Int63n(int64(100 * time.Millisecond)))
+	time.Sleep(base + jitter)
+}
+
+func (driver *HTTPDriver) Check(version *semver.Version) ([]semver.Version, error) {
+	currentVersion, _, err := driver.get()
+	if err != nil {
+		return nil, err
+	}
+
+	if version == nil || currentVersion.GTE(*version) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	return []semver.Version{}, nil
+}
+
+var errETagMismatch = fmt.Errorf("etag changed since last read")
+
+func (driver *HTTPDriver) get() (semver.Version, string, error) {
+	req, err := http.NewRequest("GET", driver.URI, nil)
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+
+	driver.authenticate(req)
+
+	client, err := driver.httpClient()
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return driver.InitialVersion, "", nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return semver.Version{}, "", fmt.Errorf("getting version: unexpected status: %s", resp.Status)
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+
+	currentVersion, err := semver.Parse(string(bytes.TrimSpace(contents)))
+	if err != nil {
+		return semver.Version{}, "", fmt.Errorf("parsing current version: %s", err)
+	}
+
+	return currentVersion, resp.Header.Get("ETag"), nil
+}
+
+func (driver *HTTPDriver) put(newVersion semver.Version, etag string) error {
+	client, err := driver.httpClient()
+	if err != nil {
+		return err
+	}
+
+	if driver.WebDAV {
+		lockToken, err := driver.lock(client)
+		if err != nil {
+			return err
+		}
+		defer driver.unlock(client, lockToken)
+	}
+
+	req, err := http.NewRequest("PUT", driver.URI, bytes.NewReader([]byte(newVersion.String())))
+	if err != nil {
+		return err
+	}
+
+	driver.authenticate(req)
+
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	} else {
+		req.Header.Set("If-None-Match", "*")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return errETagMismatch
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("setting version: unexpected status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// lockInfoBody is the minimal <D:lockinfo> payload RFC 4918 requires a
+// LOCK request to carry; without one, real WebDAV servers (Apache
+// mod_dav, Nexus, Artifactory) reject the request outright instead of
+// granting a lock.
+const lockInfoBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:lockinfo xmlns:D="DAV:">
+  <D:lockscope><D:exclusive/></D:lockscope>
+  <D:locktype><D:write/></D:locktype>
+  <D:owner><D:href>concourse-semver-resource</D:href></D:owner>
+</D:lockinfo>`
+
+func (driver *HTTPDriver) lock(client *http.Client) (string, error) {
+	req, err := http.NewRequest("LOCK", driver.URI, bytes.NewReader([]byte(lockInfoBody)))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Depth", "0")
+
+	driver.authenticate(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("locking resource: unexpected status: %s", resp.Status)
+	}
+
+	return resp.Header.Get("Lock-Token"), nil
+}
+
+func (driver *HTTPDriver) unlock(client *http.Client, lockToken string) error {
+	req, err := http.NewRequest("UNLOCK", driver.URI, nil)
+	if err != nil {
+		return err
+	}
+
+	driver.authenticate(req)
+
+	if lockToken != "" {
+		req.Header.Set("Lock-Token", lockToken)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (driver *HTTPDriver) authenticate(req *http.Request) {
+	if driver.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+driver.Token)
+	} else if driver.Username != "" || driver.Password != "" {
+		req.SetBasicAuth(driver.Username, driver.Password)
+	}
+}
+
+func (driver *HTTPDriver) httpClient() (*http.Client, error) {
+	if driver.client != nil {
+		return driver.client, nil
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if driver.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(driver.CACert)) {
+			return nil, fmt.Errorf("parsing ca_cert: no certificates found")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if driver.ClientCert != "" && driver.ClientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(driver.ClientCert), []byte(driver.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("parsing client_cert/client_key: %s", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	driver.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	return driver.client, nil
+}
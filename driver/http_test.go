@@ -0,0 +1,167 @@
+package driver
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/version"
+)
+
+// versionStore is a tiny in-memory "remote resource" with an
+// incrementing ETag, used to drive the HTTP/WebDAV driver tests
+// without a real server.
+type versionStore struct {
+	mu       sync.Mutex
+	contents string
+	etag     int
+
+	mismatchesBeforeSuccess int
+	lockSeen                bool
+	lockBody                string
+	lockDepth               string
+	unlockSeen              bool
+}
+
+func newVersionStoreServer(t *testing.T, store *versionStore) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+
+		switch r.Method {
+		case "GET":
+			if store.contents == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			w.Header().Set("ETag", strconv.Itoa(store.etag))
+			w.Write([]byte(store.contents))
+
+		case "PUT":
+			if store.mismatchesBeforeSuccess > 0 {
+				store.mismatchesBeforeSuccess--
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+
+			ifMatch := r.Header.Get("If-Match")
+			if store.contents != "" && ifMatch != strconv.Itoa(store.etag) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			store.contents = string(body)
+			store.etag++
+			w.WriteHeader(http.StatusOK)
+
+		case "LOCK":
+			store.lockSeen = true
+			store.lockDepth = r.Header.Get("Depth")
+
+			body, _ := ioutil.ReadAll(r.Body)
+			store.lockBody = string(body)
+
+			w.Header().Set("Lock-Token", "opaquelocktoken:mock")
+			w.WriteHeader(http.StatusOK)
+
+		case "UNLOCK":
+			store.unlockSeen = true
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+}
+
+func TestHTTPDriverBumpSimple(t *testing.T) {
+	store := &versionStore{contents: "1.2.3", etag: 1}
+	server := newVersionStoreServer(t, store)
+	defer server.Close()
+
+	d := &HTTPDriver{URI: server.URL}
+
+	newVersion, err := d.Bump(version.Bump{Major: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if newVersion.String() != "2.0.0" {
+		t.Fatalf("expected 2.0.0, got %s", newVersion.String())
+	}
+
+	if store.contents != "2.0.0" {
+		t.Fatalf("expected remote contents to be updated, got %s", store.contents)
+	}
+}
+
+func TestHTTPDriverCASRetriesThenSucceeds(t *testing.T) {
+	store := &versionStore{contents: "1.0.0", etag: 1, mismatchesBeforeSuccess: 2}
+	server := newVersionStoreServer(t, store)
+	defer server.Close()
+
+	d := &HTTPDriver{URI: server.URL}
+
+	newVersion, err := d.Bump(version.Bump{Minor: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if newVersion.String() != "1.1.0" {
+		t.Fatalf("expected 1.1.0, got %s", newVersion.String())
+	}
+}
+
+func TestHTTPDriverCASGivesUpAfterMaxAttempts(t *testing.T) {
+	store := &versionStore{contents: "1.0.0", etag: 1, mismatchesBeforeSuccess: maxCASAttempts + 1}
+	server := newVersionStoreServer(t, store)
+	defer server.Close()
+
+	d := &HTTPDriver{URI: server.URL}
+
+	if _, err := d.Bump(version.Bump{Minor: true}); err == nil {
+		t.Fatalf("expected an error after exhausting CAS attempts")
+	}
+}
+
+func TestHTTPDriverWebDAVLockSequence(t *testing.T) {
+	store := &versionStore{contents: "1.0.0", etag: 1}
+	server := newVersionStoreServer(t, store)
+	defer server.Close()
+
+	d := &HTTPDriver{URI: server.URL, WebDAV: true}
+
+	if err := d.Set(semver.MustParse("2.0.0")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !store.lockSeen {
+		t.Fatalf("expected a LOCK request before the PUT")
+	}
+
+	if !store.unlockSeen {
+		t.Fatalf("expected an UNLOCK request after the PUT")
+	}
+
+	if store.lockDepth != "0" {
+		t.Fatalf("expected Depth: 0 on the LOCK request, got %q", store.lockDepth)
+	}
+
+	if store.lockBody == "" {
+		t.Fatalf("expected a lockinfo XML body on the LOCK request")
+	}
+}
@@ -0,0 +1,257 @@
+package driver
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/version"
+)
+
+// S3Driver stores the current version as the contents of a single S3
+// object. When UseS3Versioning is enabled, every Bump/Set is kept as a
+// distinct S3 object version (optionally under Object Lock), so Check
+// can reconstruct the full semver history rather than only returning
+// the version currently at Key.
+type S3Driver struct {
+	InitialVersion semver.Version
+
+	Sess                 *session.Session
+	Svc                  s3iface.S3API
+	BucketName           string
+	Key                  string
+	ServerSideEncryption string
+
+	UseS3Versioning       bool
+	ObjectLockMode        string
+	ObjectLockRetainUntil string
+
+	KMSKeyID       string
+	EncryptionMode string
+}
+
+func (driver *S3Driver) Bump(bump version.Bump) (semver.Version, error) {
+	currentVersion, _, err := driver.getVersion()
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	newVersion := bump.Apply(currentVersion)
+
+	err = driver.setVersion(newVersion)
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	return newVersion, nil
+}
+
+func (driver *S3Driver) Set(newVersion semver.Version) error {
+	return driver.setVersion(newVersion)
+}
+
+func (driver *S3Driver) Check(version *semver.Version) ([]semver.Version, error) {
+	if driver.UseS3Versioning {
+		return driver.checkVersioned(version)
+	}
+
+	currentVersion, exists, err := driver.getVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		currentVersion = driver.InitialVersion
+	}
+
+	if version == nil || currentVersion.GTE(*version) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	return []semver.Version{}, nil
+}
+
+func (driver *S3Driver) getVersion() (semver.Version, bool, error) {
+	resp, err := driver.Svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(driver.BucketName),
+		Key:    aws.String(driver.Key),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok {
+			if awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound" {
+				return driver.InitialVersion, false, nil
+			}
+		}
+
+		return semver.Version{}, false, err
+	}
+
+	defer resp.Body.Close()
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return semver.Version{}, false, err
+	}
+
+	if driver.EncryptionMode == "cse-kms" {
+		contents, err = openAWSEnvelope(driver.Sess, driver.KMSKeyID, contents)
+		if err != nil {
+			return semver.Version{}, false, err
+		}
+	}
+
+	currentVersion, err := semver.Parse(string(bytes.TrimSpace(contents)))
+	if err != nil {
+		return semver.Version{}, false, fmt.Errorf("parsing current version: %s", err)
+	}
+
+	return currentVersion, true, nil
+}
+
+func (driver *S3Driver) setVersion(newVersion semver.Version) error {
+	contents := []byte(newVersion.String())
+
+	if driver.EncryptionMode == "cse-kms" {
+		sealed, err := sealAWSEnvelope(driver.Sess, driver.KMSKeyID, contents)
+		if err != nil {
+			return err
+		}
+
+		contents = sealed
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(driver.BucketName),
+		Key:    aws.String(driver.Key),
+		Body:   bytes.NewReader(contents),
+	}
+
+	if driver.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(driver.ServerSideEncryption)
+	}
+
+	if driver.UseS3Versioning && driver.ObjectLockMode != "" {
+		retainUntil, err := driver.objectLockRetainUntilDate()
+		if err != nil {
+			return err
+		}
+
+		input.ObjectLockMode = aws.String(driver.ObjectLockMode)
+		input.ObjectLockRetainUntilDate = aws.Time(retainUntil)
+	}
+
+	resp, err := driver.Svc.PutObject(input)
+	if err != nil {
+		return err
+	}
+
+	if driver.UseS3Versioning && resp.VersionId == nil {
+		return fmt.Errorf("bucket %s is not version-enabled", driver.BucketName)
+	}
+
+	return nil
+}
+
+func (driver *S3Driver) objectLockRetainUntilDate() (time.Time, error) {
+	retainFor, err := time.ParseDuration(driver.ObjectLockRetainUntil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid object lock retain-until duration (%s): %s", driver.ObjectLockRetainUntil, err)
+	}
+
+	return time.Now().Add(retainFor), nil
+}
+
+// checkVersioned walks every S3 object version ever written to Key, from
+// oldest to newest, and reconstructs the semver history from their
+// contents rather than trusting only the current object.
+func (driver *S3Driver) checkVersioned(version *semver.Version) ([]semver.Version, error) {
+	var versions []*s3.ObjectVersion
+
+	err := driver.Svc.ListObjectVersionsPages(&s3.ListObjectVersionsInput{
+		Bucket: aws.String(driver.BucketName),
+		Prefix: aws.String(driver.Key),
+	}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		for _, v := range page.Versions {
+			if aws.StringValue(v.Key) == driver.Key {
+				versions = append(versions, v)
+			}
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].LastModified.Before(*versions[j].LastModified)
+	})
+
+	history := []semver.Version{}
+	var lastErr error
+
+	for _, v := range versions {
+		resp, err := driver.Svc.GetObject(&s3.GetObjectInput{
+			Bucket:    aws.String(driver.BucketName),
+			Key:       aws.String(driver.Key),
+			VersionId: v.VersionId,
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		contents, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if driver.EncryptionMode == "cse-kms" {
+			contents, err = openAWSEnvelope(driver.Sess, driver.KMSKeyID, contents)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+		}
+
+		parsed, err := semver.Parse(string(bytes.TrimSpace(contents)))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		history = append(history, parsed)
+	}
+
+	if len(history) == 0 {
+		if lastErr != nil {
+			return nil, fmt.Errorf("reconstructing version history: %s", lastErr)
+		}
+
+		return []semver.Version{driver.InitialVersion}, nil
+	}
+
+	if version == nil {
+		return []semver.Version{history[len(history)-1]}, nil
+	}
+
+	filtered := []semver.Version{}
+	for _, v := range history {
+		if v.GTE(*version) {
+			filtered = append(filtered, v)
+		}
+	}
+
+	return filtered, nil
+}
@@ -0,0 +1,290 @@
+package driver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/concourse/semver-resource/models"
+)
+
+func clearAWSEnv(t *testing.T) {
+	t.Helper()
+
+	for _, key := range []string{
+		"AWS_ACCESS_KEY_ID",
+		"AWS_SECRET_ACCESS_KEY",
+		"AWS_SESSION_TOKEN",
+		"AWS_ROLE_ARN",
+		"AWS_WEB_IDENTITY_TOKEN_FILE",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI",
+	} {
+		os.Unsetenv(key)
+	}
+}
+
+// TestS3CredentialsStaticTakesPrecedence verifies that explicit
+// AccessKeyID/SecretAccessKey on the source wins over everything else
+// in the chain, without needing a network round trip to prove it.
+func TestS3CredentialsStaticTakesPrecedence(t *testing.T) {
+	clearAWSEnv(t)
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "env-key")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	defer clearAWSEnv(t)
+
+	sess := session.Must(session.NewSession())
+
+	creds := s3Credentials(models.Source{
+		AccessKeyID:     "static-key",
+		SecretAccessKey: "static-secret",
+	}, sess)
+
+	value, err := creds.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if value.AccessKeyID != "static-key" {
+		t.Fatalf("expected static-key, got %s", value.AccessKeyID)
+	}
+}
+
+// TestS3CredentialsEnvFallback verifies that when no static keys are
+// configured, the EnvProvider step of the chain is exercised.
+func TestS3CredentialsEnvFallback(t *testing.T) {
+	clearAWSEnv(t)
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "env-key")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	defer clearAWSEnv(t)
+
+	sess := session.Must(session.NewSession())
+
+	creds := s3Credentials(models.Source{}, sess)
+
+	value, err := creds.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if value.AccessKeyID != "env-key" {
+		t.Fatalf("expected env-key, got %s", value.AccessKeyID)
+	}
+}
+
+// TestS3CredentialsWebIdentity exercises the IRSA/web-identity step of
+// the chain against a mock STS server.
+func TestS3CredentialsWebIdentity(t *testing.T) {
+	clearAWSEnv(t)
+
+	tokenFile, err := ioutil.TempFile("", "web-identity-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.Remove(tokenFile.Name())
+
+	if _, err := tokenFile.WriteString("fake-jwt"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tokenFile.Close()
+
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, `<AssumeRoleWithWebIdentityResponse>
+			<AssumeRoleWithWebIdentityResult>
+				<Credentials>
+					<AccessKeyId>irsa-key</AccessKeyId>
+					<SecretAccessKey>irsa-secret</SecretAccessKey>
+					<SessionToken>irsa-token</SessionToken>
+					<Expiration>`+time.Now().Add(time.Hour).Format(time.RFC3339)+`</Expiration>
+				</Credentials>
+			</AssumeRoleWithWebIdentityResult>
+		</AssumeRoleWithWebIdentityResponse>`)
+	}))
+	defer sts.Close()
+
+	os.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/irsa-role")
+	os.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile.Name())
+	defer clearAWSEnv(t)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:   aws.String(sts.URL),
+		Region:     aws.String("us-east-1"),
+		DisableSSL: aws.Bool(true),
+	}))
+
+	creds := s3Credentials(models.Source{}, sess)
+
+	value, err := creds.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if value.AccessKeyID != "irsa-key" {
+		t.Fatalf("expected irsa-key, got %s", value.AccessKeyID)
+	}
+}
+
+// TestS3CredentialsECS exercises the ECS container-credentials step of
+// the chain against a mock endpoint.
+func TestS3CredentialsECS(t *testing.T) {
+	clearAWSEnv(t)
+
+	ecs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{
+			"AccessKeyId": "ecs-key",
+			"SecretAccessKey": "ecs-secret",
+			"Token": "ecs-token",
+			"Expiration": "%s"
+		}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer ecs.Close()
+
+	os.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", ecs.URL)
+	defer clearAWSEnv(t)
+
+	sess := session.Must(session.NewSession())
+
+	creds := s3Credentials(models.Source{}, sess)
+
+	value, err := creds.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if value.AccessKeyID != "ecs-key" {
+		t.Fatalf("expected ecs-key, got %s", value.AccessKeyID)
+	}
+}
+
+// TestS3CredentialsSharedFile exercises the SharedCredentialsProvider
+// step of the chain against a source-specified profile and file.
+func TestS3CredentialsSharedFile(t *testing.T) {
+	clearAWSEnv(t)
+	defer clearAWSEnv(t)
+
+	credsFile, err := ioutil.TempFile("", "shared-credentials")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.Remove(credsFile.Name())
+
+	_, err = credsFile.WriteString("[mock-profile]\naws_access_key_id = shared-key\naws_secret_access_key = shared-secret\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	credsFile.Close()
+
+	sess := session.Must(session.NewSession())
+
+	creds := s3Credentials(models.Source{
+		SharedCredentialsFile: credsFile.Name(),
+		Profile:               "mock-profile",
+	}, sess)
+
+	value, err := creds.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if value.AccessKeyID != "shared-key" {
+		t.Fatalf("expected shared-key, got %s", value.AccessKeyID)
+	}
+}
+
+// TestS3CredentialsIMDS exercises the EC2 instance-role (IMDS) step of
+// the chain against a mock metadata server.
+func TestS3CredentialsIMDS(t *testing.T) {
+	clearAWSEnv(t)
+	defer clearAWSEnv(t)
+
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			fmt.Fprint(w, "imds-role")
+		case "/latest/meta-data/iam/security-credentials/imds-role":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{
+				"Code": "Success",
+				"AccessKeyId": "imds-key",
+				"SecretAccessKey": "imds-secret",
+				"Token": "imds-token",
+				"Expiration": "%s"
+			}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer imds.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:   aws.String(imds.URL),
+		Region:     aws.String("us-east-1"),
+		DisableSSL: aws.Bool(true),
+	}))
+
+	creds := s3Credentials(models.Source{}, sess)
+
+	value, err := creds.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if value.AccessKeyID != "imds-key" {
+		t.Fatalf("expected imds-key, got %s", value.AccessKeyID)
+	}
+}
+
+// TestS3CredentialsAssumeRole verifies that a configured RoleArn wraps
+// the resolved base credentials in an AssumeRole call.
+func TestS3CredentialsAssumeRole(t *testing.T) {
+	clearAWSEnv(t)
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "base-key")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "base-secret")
+	defer clearAWSEnv(t)
+
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, `<AssumeRoleResponse>
+			<AssumeRoleResult>
+				<Credentials>
+					<AccessKeyId>assumed-key</AccessKeyId>
+					<SecretAccessKey>assumed-secret</SecretAccessKey>
+					<SessionToken>assumed-token</SessionToken>
+					<Expiration>`+time.Now().Add(time.Hour).Format(time.RFC3339)+`</Expiration>
+				</Credentials>
+			</AssumeRoleResult>
+		</AssumeRoleResponse>`)
+	}))
+	defer sts.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:   aws.String(sts.URL),
+		Region:     aws.String("us-east-1"),
+		DisableSSL: aws.Bool(true),
+	}))
+
+	creds := s3Credentials(models.Source{
+		RoleArn: "arn:aws:iam::123456789012:role/assumed-role",
+	}, sess)
+
+	value, err := creds.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if value.AccessKeyID != "assumed-key" {
+		t.Fatalf("expected assumed-key, got %s", value.AccessKeyID)
+	}
+}
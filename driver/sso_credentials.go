@@ -0,0 +1,243 @@
+package driver
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sso"
+	"github.com/aws/aws-sdk-go/service/ssooidc"
+
+	"github.com/concourse/semver-resource/models"
+)
+
+const ssoProviderName = "SSOProvider"
+
+// ssoTokenCache is the on-disk representation of a cached SSO OIDC
+// access token, keyed by sha1(StartURL) under ~/.aws/sso/cache, in the
+// same layout the AWS CLI uses so that tokens obtained interactively
+// can be reused here (and vice versa).
+type ssoTokenCache struct {
+	StartURL     string    `json:"startUrl"`
+	Region       string    `json:"region"`
+	AccessToken  string    `json:"accessToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	ClientID     string    `json:"clientId"`
+	ClientSecret string    `json:"clientSecret"`
+}
+
+// ssoCredentialsProvider resolves AWS credentials via AWS SSO, reading
+// the SSO OIDC access token from the on-disk cache and exchanging it for
+// short-lived role credentials with sso.GetRoleCredentials. It refreshes
+// the role credentials automatically as they expire; the access token
+// itself is not refreshed here, and Retrieve fails once it has expired.
+type ssoCredentialsProvider struct {
+	credentials.Expiry
+
+	StartURL  string
+	AccountID string
+	RoleName  string
+	Region    string
+
+	sso     *sso.SSO
+	ssoOIDC *ssooidc.SSOOIDC
+}
+
+func newSSOCredentialsProvider(sess *session.Session, source models.Source) *ssoCredentialsProvider {
+	region := source.SSORegion
+	if region == "" {
+		region = source.RegionName
+	}
+
+	regionalSess := sess.Copy(&aws.Config{Region: aws.String(region)})
+
+	return &ssoCredentialsProvider{
+		StartURL:  source.SSOStartURL,
+		AccountID: source.SSOAccountID,
+		RoleName:  source.SSORoleName,
+		Region:    region,
+
+		sso:     sso.New(regionalSess),
+		ssoOIDC: ssooidc.New(regionalSess),
+	}
+}
+
+func (p *ssoCredentialsProvider) Retrieve() (credentials.Value, error) {
+	accessToken, err := p.accessToken()
+	if err != nil {
+		return credentials.Value{ProviderName: ssoProviderName}, fmt.Errorf("getting sso access token: %s", err)
+	}
+
+	roleCreds, err := p.sso.GetRoleCredentials(&sso.GetRoleCredentialsInput{
+		AccessToken: aws.String(accessToken),
+		AccountId:   aws.String(p.AccountID),
+		RoleName:    aws.String(p.RoleName),
+	})
+	if err != nil {
+		return credentials.Value{ProviderName: ssoProviderName}, fmt.Errorf("getting sso role credentials: %s", err)
+	}
+
+	expiresAt := time.Unix(aws.Int64Value(roleCreds.RoleCredentials.Expiration)/1000, 0)
+	p.SetExpiration(expiresAt.Add(-1*time.Minute), 0)
+
+	return credentials.Value{
+		AccessKeyID:     aws.StringValue(roleCreds.RoleCredentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(roleCreds.RoleCredentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(roleCreds.RoleCredentials.SessionToken),
+		ProviderName:    ssoProviderName,
+	}, nil
+}
+
+// accessToken returns a cached, unexpired SSO OIDC access token for
+// StartURL. Concourse check/in/out containers are unattended, so this
+// never starts a fresh device authorization flow itself (that would
+// block the step for up to the grant's ExpiresIn with nobody present
+// to approve it); instead it errors out telling the operator to
+// populate the cache out-of-band, e.g. by running `aws sso login`
+// against the same start URL before the pipeline runs.
+func (p *ssoCredentialsProvider) accessToken() (string, error) {
+	cachePath, err := ssoCachePath(p.StartURL)
+	if err != nil {
+		return "", err
+	}
+
+	cache, err := readSSOTokenCache(cachePath)
+	if err != nil {
+		return "", fmt.Errorf("no cached sso access token for %s (run `aws sso login` out-of-band first): %s", p.StartURL, err)
+	}
+
+	if cache.AccessToken == "" || !time.Now().Before(cache.ExpiresAt.Add(-1*time.Minute)) {
+		return "", fmt.Errorf("cached sso access token for %s has expired (run `aws sso login` out-of-band first)", p.StartURL)
+	}
+
+	return cache.AccessToken, nil
+}
+
+// LoginSSO runs the interactive device authorization flow for
+// startURL and persists the resulting access token to the same cache
+// accessToken reads from. It is meant to be invoked out-of-band by an
+// operator (or a dedicated login step with a human attached), never
+// from an unattended check/in/out.
+func LoginSSO(sess *session.Session, source models.Source) error {
+	p := newSSOCredentialsProvider(sess, source)
+
+	cachePath, err := ssoCachePath(p.StartURL)
+	if err != nil {
+		return err
+	}
+
+	cache, err := p.deviceAuthorize()
+	if err != nil {
+		return err
+	}
+
+	return writeSSOTokenCache(cachePath, cache)
+}
+
+func (p *ssoCredentialsProvider) deviceAuthorize() (*ssoTokenCache, error) {
+	client, err := p.ssoOIDC.RegisterClient(&ssooidc.RegisterClientInput{
+		ClientName: aws.String("concourse-semver-resource"),
+		ClientType: aws.String("public"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("registering oidc client: %s", err)
+	}
+
+	authorization, err := p.ssoOIDC.StartDeviceAuthorization(&ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     client.ClientId,
+		ClientSecret: client.ClientSecret,
+		StartUrl:     aws.String(p.StartURL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("starting device authorization: %s", err)
+	}
+
+	interval := time.Duration(aws.Int64Value(authorization.Interval)) * time.Second
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+
+	if verificationURI := aws.StringValue(authorization.VerificationUriComplete); verificationURI != "" {
+		fmt.Fprintf(os.Stderr, "please authenticate via AWS SSO: %s\n", verificationURI)
+	} else {
+		fmt.Fprintf(os.Stderr, "please authenticate via AWS SSO: %s (code: %s)\n",
+			aws.StringValue(authorization.VerificationUri), aws.StringValue(authorization.UserCode))
+	}
+
+	deadline := time.Now().Add(time.Duration(aws.Int64Value(authorization.ExpiresIn)) * time.Second)
+
+	for time.Now().Before(deadline) {
+		token, err := p.ssoOIDC.CreateToken(&ssooidc.CreateTokenInput{
+			ClientId:     client.ClientId,
+			ClientSecret: client.ClientSecret,
+			GrantType:    aws.String("urn:ietf:params:oauth:grant-type:device_code"),
+			DeviceCode:   authorization.DeviceCode,
+		})
+		if err != nil {
+			if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == ssooidc.ErrCodeAuthorizationPendingException {
+				time.Sleep(interval)
+				continue
+			}
+
+			return nil, fmt.Errorf("creating sso token: %s", err)
+		}
+
+		return &ssoTokenCache{
+			StartURL:     p.StartURL,
+			Region:       p.Region,
+			AccessToken:  aws.StringValue(token.AccessToken),
+			ExpiresAt:    time.Now().Add(time.Duration(aws.Int64Value(token.ExpiresIn)) * time.Second),
+			ClientID:     aws.StringValue(client.ClientId),
+			ClientSecret: aws.StringValue(client.ClientSecret),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("timed out waiting for sso device authorization")
+}
+
+func ssoCachePath(startURL string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum([]byte(startURL))
+
+	return filepath.Join(home, ".aws", "sso", "cache", hex.EncodeToString(sum[:])+".json"), nil
+}
+
+func readSSOTokenCache(path string) (*ssoTokenCache, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cache ssoTokenCache
+	if err := json.Unmarshal(contents, &cache); err != nil {
+		return nil, err
+	}
+
+	return &cache, nil
+}
+
+func writeSSOTokenCache(path string, cache *ssoTokenCache) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	contents, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, contents, 0600)
+}
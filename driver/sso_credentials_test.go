@@ -0,0 +1,178 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/concourse/semver-resource/models"
+)
+
+// newMockSSOServer stands in for both the SSO and SSO-OIDC endpoints,
+// routing on path the way the real services are split across two
+// hostnames in production.
+func newMockSSOServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/client/register":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"clientId":     "mock-client-id",
+				"clientSecret": "mock-client-secret",
+			})
+		case "/device_authorization":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"deviceCode":              "mock-device-code",
+				"userCode":                "MOCK-CODE",
+				"verificationUri":         "https://mock-sso.example.com/device",
+				"verificationUriComplete": "https://mock-sso.example.com/device?code=MOCK-CODE",
+				"expiresIn":               600,
+				"interval":                0,
+			})
+		case "/token":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"accessToken": "mock-access-token",
+				"tokenType":   "Bearer",
+				"expiresIn":   3600,
+			})
+		case "/federation/credentials":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"roleCredentials": map[string]interface{}{
+					"accessKeyId":     "sso-key",
+					"secretAccessKey": "sso-secret",
+					"sessionToken":    "sso-token",
+					"expiration":      time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond),
+				},
+			})
+		default:
+			http.NotFound(w, r)
+			fmt.Fprintf(os.Stderr, "unexpected sso request: %s\n", r.URL.Path)
+		}
+	}))
+}
+
+// TestSSOCredentialsProviderRetrieve exercises GetRoleCredentials
+// against a cached access token, the way an unattended check/in/out
+// actually runs: LoginSSO (standing in for an operator's out-of-band
+// `aws sso login`) populates the cache once, then Retrieve only ever
+// reads it.
+func TestSSOCredentialsProviderRetrieve(t *testing.T) {
+	home, err := os.MkdirTemp("", "sso-home")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(home)
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", oldHome)
+
+	server := newMockSSOServer(t)
+	defer server.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:   aws.String(server.URL),
+		Region:     aws.String("us-east-1"),
+		DisableSSL: aws.Bool(true),
+	}))
+
+	source := models.Source{
+		SSOStartURL:  "https://mock-sso.example.com/start",
+		SSOAccountID: "123456789012",
+		SSORoleName:  "mock-role",
+		SSORegion:    "us-east-1",
+	}
+
+	if err := LoginSSO(sess, source); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	provider := newSSOCredentialsProvider(sess, source)
+
+	value, err := provider.Retrieve()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if value.AccessKeyID != "sso-key" {
+		t.Fatalf("expected sso-key, got %s", value.AccessKeyID)
+	}
+
+	if provider.IsExpired() {
+		t.Fatalf("expected credentials to not be expired immediately after retrieval")
+	}
+}
+
+// TestSSOCredentialsProviderFailsFastWithoutCache verifies that
+// Retrieve errors out immediately (no device authorization flow
+// attempted) when no cached token exists yet, since an unattended
+// check/in/out has nobody present to approve the grant.
+func TestSSOCredentialsProviderFailsFastWithoutCache(t *testing.T) {
+	home, err := os.MkdirTemp("", "sso-home")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(home)
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", oldHome)
+
+	provider := &ssoCredentialsProvider{StartURL: "https://mock-sso.example.com/never-logged-in"}
+
+	if _, err := provider.Retrieve(); err == nil {
+		t.Fatalf("expected an error when no cached sso token exists")
+	}
+}
+
+// TestSSOAccessTokenCacheRoundTrip verifies that a cached, unexpired
+// token written with writeSSOTokenCache is returned by accessToken.
+func TestSSOAccessTokenCacheRoundTrip(t *testing.T) {
+	home, err := os.MkdirTemp("", "sso-home")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(home)
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", oldHome)
+
+	startURL := "https://mock-sso.example.com/start"
+
+	cachePath, err := ssoCachePath(startURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cached := &ssoTokenCache{
+		StartURL:    startURL,
+		AccessToken: "cached-access-token",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	if err := writeSSOTokenCache(cachePath, cached); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	provider := &ssoCredentialsProvider{StartURL: startURL}
+
+	token, err := provider.accessToken()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if token != "cached-access-token" {
+		t.Fatalf("expected cached-access-token, got %s", token)
+	}
+}
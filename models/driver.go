@@ -0,0 +1,14 @@
+package models
+
+// Driver selects which backend FromSource builds to store the current
+// version.
+type Driver string
+
+const (
+	DriverUnspecified Driver = ""
+	DriverS3          Driver = "s3"
+	DriverGit         Driver = "git"
+	DriverSwift       Driver = "swift"
+	DriverGCS         Driver = "gcs"
+	DriverHTTP        Driver = "http"
+)
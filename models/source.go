@@ -0,0 +1,60 @@
+package models
+
+// Source is the resource configuration common to every driver. Only
+// the fields relevant to the selected Driver need be set; the rest are
+// ignored.
+type Source struct {
+	Driver         Driver `json:"driver,omitempty"`
+	InitialVersion string `json:"initial_version,omitempty"`
+
+	// S3
+	Bucket               string `json:"bucket,omitempty"`
+	Key                  string `json:"key,omitempty"`
+	AccessKeyID          string `json:"access_key_id,omitempty"`
+	SecretAccessKey      string `json:"secret_access_key,omitempty"`
+	SessionToken         string `json:"session_token,omitempty"`
+	RegionName           string `json:"region_name,omitempty"`
+	Endpoint             string `json:"endpoint,omitempty"`
+	DisableSSL           bool   `json:"disable_ssl,omitempty"`
+	ServerSideEncryption string `json:"server_side_encryption,omitempty"`
+
+	RoleArn                   string `json:"role_arn,omitempty"`
+	RoleSessionName           string `json:"role_session_name,omitempty"`
+	ExternalID                string `json:"external_id,omitempty"`
+	AssumeRoleDurationSeconds int64  `json:"assume_role_duration_seconds,omitempty"`
+
+	Profile               string `json:"profile,omitempty"`
+	SharedCredentialsFile string `json:"shared_credentials_file,omitempty"`
+
+	UseS3Versioning       bool   `json:"use_s3_versioning,omitempty"`
+	ObjectLockMode        string `json:"object_lock_mode,omitempty"`
+	ObjectLockRetainUntil string `json:"object_lock_retain_until,omitempty"`
+
+	// Client-side envelope encryption (S3 and GCS)
+	KMSKeyID       string `json:"kms_key_id,omitempty"`
+	EncryptionMode string `json:"encryption_mode,omitempty"`
+
+	SSOStartURL  string `json:"sso_start_url,omitempty"`
+	SSOAccountID string `json:"sso_account_id,omitempty"`
+	SSORoleName  string `json:"sso_role_name,omitempty"`
+	SSORegion    string `json:"sso_region,omitempty"`
+
+	// Git
+	URI        string `json:"uri,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"`
+	Username   string `json:"username,omitempty"`
+	Password   string `json:"password,omitempty"`
+	File       string `json:"file,omitempty"`
+	GitUser    string `json:"git_user,omitempty"`
+
+	// GCS
+	JSONKey string `json:"json_key,omitempty"`
+
+	// HTTP
+	WebDAV     bool   `json:"webdav,omitempty"`
+	Token      string `json:"token,omitempty"`
+	CACert     string `json:"ca_cert,omitempty"`
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+}